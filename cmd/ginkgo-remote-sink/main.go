@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command ginkgo-remote-sink is a reference implementation of the receiver
+// side of printer.RemoteJSONReporter's wire protocol: it listens for
+// report-suite POSTs and pretty-prints each suite's progress as it comes
+// in, so that REMOTE_TEST_OUT_ADDR has somewhere to point during local
+// development.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer/remotesrv"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on for report-suite POSTs")
+	flag.Parse()
+
+	srv := remotesrv.NewServer()
+	srv.OnSpec = func(suite *printer.Suite, spec printer.PartStatus) {
+		printSpec(suite.Name, spec)
+	}
+	srv.OnSuiteEnd = printSuiteSummary
+
+	log.Printf("listening on %s for report-suite POSTs", *addr)
+	log.Fatal(remotesrv.ListenAndServe(*addr, srv))
+}
+
+// printSuiteSummary renders a finished suite's final tally to stdout. Its
+// specs were already printed live as they arrived, via OnSpec.
+func printSuiteSummary(suite *printer.Suite) {
+	fmt.Printf("=== suite %q finished in %s ===\n", suite.Name, suite.RunTime)
+	if suite.Stats != nil {
+		fmt.Printf("    %d total, %d passed, %d failed, %d pending, %d skipped, %d flaked\n",
+			suite.Stats.Total, suite.Stats.Passed, suite.Stats.Failed,
+			suite.Stats.Pending, suite.Stats.Skipped, suite.Stats.Flakes)
+	}
+}
+
+// printSpec renders a single spec's outcome as one line of progress output,
+// as soon as it arrives.
+func printSpec(suiteName string, spec printer.PartStatus) {
+	texts := make([]string, len(spec.Components))
+	for i, c := range spec.Components {
+		texts[i] = c.Text
+	}
+
+	status := "ok"
+	if spec.Failure != nil {
+		status = string(spec.State)
+	}
+
+	fmt.Printf("[%s] %s: %s (%s)\n", suiteName, status, strings.Join(texts, " "), spec.RunTime)
+}
@@ -17,69 +17,56 @@ limitations under the License.
 package printer
 
 import (
-	"fmt"
-	"os"
-	"net/http"
-	"encoding/json"
-	"bytes"
+	"sync"
 	"time"
 
 	"github.com/onsi/ginkgo/config"
 	"github.com/onsi/ginkgo/types"
-
 )
 
 // RemoteJSONReport sends JSON status reports to http://localhost:<Port>/report-suite.
-// Requests are POSTed in the message format described by SuiteMessage.
+// Messages are sent in the format described by SuiteMessage, over a
+// pluggable Transport (HTTP POST by default; see NewTransport for the
+// other options).
+//
+// An update carrying newly completed specs is sent as soon as the
+// background sender is free; updates carrying no new specs (e.g. only a
+// BeforeSuite/AfterSuite result) are sent at most 1 per second. Sending
+// start-/end-suite messages is not limited.
 //
-// Updates are sent at most 1 per second, to avoid overloading the server with
-// quick tests, but sending start-/end-suite messages are not limited.
+// Sends happen on a background goroutine, so a slow or unavailable server
+// never blocks the spec under test: updates pile up in a bounded ring
+// buffer (see BufferSize) and are retried with exponential backoff until
+// they can be flushed, at which point any buffered updates are sent
+// together. The actual machinery lives in Sender, which is shared with
+// printer/v2's Ginkgo v2 reporter.
 type RemoteJSONReporter struct {
 	Addr string
 
-	suite Suite
-	nextSpecs []PartStatus
-	lastUpdate time.Time
+	// Transport overrides how messages are sent. If nil, one is built from
+	// Addr and the REMOTE_TEST_OUT_TRANSPORT, REMOTE_TEST_OUT_SCHEME, and
+	// REMOTE_TEST_OUT_TOKEN environment variables -- see NewTransport.
+	Transport Transport
+	// BufferSize bounds how many not-yet-acknowledged spec updates are
+	// buffered for replay after a reconnect. Defaults to
+	// defaultBufferSize.
+	BufferSize int
+
+	senderOnce sync.Once
+	sender     Sender
 }
 
-func (r *RemoteJSONReporter) req(action SuiteAction, reason string) {
-	if err := r.reqInt(action); err != nil {
-		fmt.Fprintf(os.Stderr, "unable to send %s for suite %q from %s: %v", action, r.suite.Name, reason, err)
-	}
-}
-
-func (r *RemoteJSONReporter) reqInt(action SuiteAction) error {
-	if action == ActionSuiteUpdate && time.Now().Sub(r.lastUpdate) < updateThreshold {
-		return nil
-	}
-
-	msg := SuiteMessage{Action: action, Suite: &r.suite}
-	msgJSON, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("unable to marshal %s request: %w", action, err)
-	}
-
-	url := fmt.Sprintf("http://%s/report-suite", r.Addr)
-	resp, err := http.Post(url, "application/json", bytes.NewReader(msgJSON))
-	if err != nil {
-		return fmt.Errorf("unable to post %s request: %w", action, err)
-	}
-
-	switch resp.StatusCode {
-	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
-		r.lastUpdate = time.Now()
-		r.suite.MoreTestCases = r.suite.MoreTestCases[:0]
-		return nil
-	default:
-		return fmt.Errorf("server did not accept %s request: %q", action, resp.Status)
-	}
+// sndr lazily builds the Sender that does the actual work, from the
+// fields above.
+func (r *RemoteJSONReporter) sndr() *Sender {
+	r.senderOnce.Do(func() {
+		r.sender = Sender{Addr: r.Addr, Transport: r.Transport, BufferSize: r.BufferSize}
+	})
+	return &r.sender
 }
 
 func (r *RemoteJSONReporter) SpecSuiteWillBegin(config config.GinkgoConfigType, summary *types.SuiteSummary) {
-	r.suite.Name = summary.SuiteDescription
-	r.suite.ID = summary.SuiteID
-
-	r.req(ActionSuiteStart, "Suite (start)")
+	r.sndr().BeginSuite(summary.SuiteDescription, summary.SuiteID, nil)
 }
 func (r *RemoteJSONReporter) SpecWillRun(specSummary *types.SpecSummary) {
 	// No-op
@@ -91,19 +78,16 @@ func (r *RemoteJSONReporter) BeforeSuiteDidRun(sum *types.SetupSummary) {
 			{Location: locToLoc(sum.CodeLocation)},
 		},
 		RunTime: sum.RunTime,
-		Output: sum.CapturedOutput,
+		Output:  sum.CapturedOutput,
 	}.withState(sum.State, sum.Failure)
-	r.suite.BeforeSuite = &stat
-	r.req(ActionSuiteUpdate, "BeforeSuite")
+	r.sndr().SetBeforeSuite(stat)
 }
 func (r *RemoteJSONReporter) SpecDidComplete(sum *types.SpecSummary) {
-	r.suite.MoreTestCases = append(r.suite.MoreTestCases, PartStatus{
+	r.sndr().PushSpec(PartStatus{
 		Components: summaryToComponents(sum.ComponentTexts, sum.ComponentCodeLocations),
-		RunTime: sum.RunTime,
-		Output: sum.CapturedOutput,
+		RunTime:    sum.RunTime,
+		Output:     sum.CapturedOutput,
 	}.withState(sum.State, sum.Failure))
-
-	r.req(ActionSuiteUpdate, "Spec")
 }
 func (r *RemoteJSONReporter) AfterSuiteDidRun(sum *types.SetupSummary) {
 	stat := PartStatus{
@@ -111,22 +95,19 @@ func (r *RemoteJSONReporter) AfterSuiteDidRun(sum *types.SetupSummary) {
 			{Location: locToLoc(sum.CodeLocation)},
 		},
 		RunTime: sum.RunTime,
-		Output: sum.CapturedOutput,
+		Output:  sum.CapturedOutput,
 	}.withState(sum.State, sum.Failure)
-	r.suite.BeforeSuite = &stat
-	r.req(ActionSuiteUpdate, "AfterSuite")
+	r.sndr().SetAfterSuite(stat)
 }
 func (r *RemoteJSONReporter) SpecSuiteDidEnd(sum *types.SuiteSummary) {
-	r.suite.RunTime = sum.RunTime
-	r.suite.Stats = &SuiteStats{
-		Total:            sum.NumberOfTotalSpecs,
-		Pending:          sum.NumberOfPendingSpecs,
-		Skipped:          sum.NumberOfSkippedSpecs,
-		Passed:           sum.NumberOfPassedSpecs,
-		Failed:           sum.NumberOfFailedSpecs,
-		Flakes:           sum.NumberOfFlakedSpecs,
-	}
-	r.req(ActionSuiteEnd, "Suite (end)")
+	r.sndr().EndSuite(sum.RunTime, &SuiteStats{
+		Total:   sum.NumberOfTotalSpecs,
+		Pending: sum.NumberOfPendingSpecs,
+		Skipped: sum.NumberOfSkippedSpecs,
+		Passed:  sum.NumberOfPassedSpecs,
+		Failed:  sum.NumberOfFailedSpecs,
+		Flakes:  sum.NumberOfFlakedSpecs,
+	}, nil)
 }
 
 // summaryToComponents bundles ComponentTexts & ComponentCodeLocations to
@@ -172,6 +153,10 @@ const (
 	SpecStatePanicked SpecState = "panicked"
 	SpecStateTimedOut SpecState = "timed-out"
 	SpecStatePending  SpecState = "pending"
+	// SpecStateAborted and SpecStateInterrupted only occur in Ginkgo v2
+	// reports -- v1 has no equivalent.
+	SpecStateAborted     SpecState = "aborted"
+	SpecStateInterrupted SpecState = "interrupted"
 )
 
 // ComponentType represents a single "container" in the path
@@ -215,7 +200,7 @@ type Suite struct {
 	// BeforeSuite is the result of running the BeforeSuite, if any.
 	BeforeSuite *PartStatus `json:"beforeSuite,omitempty"`
 	// AfterSuite is the result of running the AfterSuite, if any.
-	AfterSuite *PartStatus `json:"beforeSuite,omitempty"`
+	AfterSuite *PartStatus `json:"afterSuite,omitempty"`
 	// MoreTestCases contains another chunk of completed testcases.
 	// Any test cases present are new, should be appended to the list --
 	// they will not be sent again if the response from the server
@@ -225,6 +210,15 @@ type Suite struct {
 	// Stats are the final tallies of test cases run.
 	// Only set on "suite-end".
 	Stats *SuiteStats `json:"stats,omitempty"`
+
+	// Labels are the suite-level labels attached to this suite (Ginkgo v2's
+	// SuiteLabels). Only populated by v2 reporters -- left empty by the v1
+	// RemoteJSONReporter.
+	Labels []string `json:"labels,omitempty"`
+	// SpecialFailureReasons carries out-of-band failure reasons that apply
+	// to the suite as a whole (e.g. an aborted run), as reported by Ginkgo
+	// v2's SpecialSuiteFailureReasons. Only populated by v2 reporters.
+	SpecialFailureReasons []string `json:"specialFailureReasons,omitempty"`
 }
 
 // SuiteStats contains the final tallies of test cases run, broken down by
@@ -268,6 +262,35 @@ type PartStatus struct {
 
 	// Output contains the captured printed output during the test.
 	Output string `json:"output,omitempty"`
+
+	// Labels are the labels attached to this spec (Ginkgo v2's per-spec
+	// Labels, i.e. the union of its container and leaf node labels). Only
+	// populated by v2 reporters.
+	Labels []string `json:"labels,omitempty"`
+	// ReportEntries contains the structured entries (ginkgo.AddReportEntry)
+	// and timeline output attached to this spec. Only populated by v2
+	// reporters.
+	ReportEntries []ReportEntry `json:"reportEntries,omitempty"`
+	// ParallelProcess is the (1-indexed) parallel Ginkgo process that ran
+	// this spec. Only populated by v2 reporters.
+	ParallelProcess int `json:"parallelProcess,omitempty"`
+}
+
+// ReportEntry mirrors a single entry from Ginkgo v2's spec timeline -- this
+// covers both explicit ginkgo.AddReportEntry calls and captured progress
+// reports/GinkgoWriter output, serialized for consumption by downstream
+// servers that don't want to depend on Ginkgo's own types.
+type ReportEntry struct {
+	// Name identifies the entry (e.g. the name passed to AddReportEntry, or
+	// a synthetic name like "GinkgoWriter Output").
+	Name string `json:"name"`
+	// Location is where the entry was recorded.
+	Location Location `json:"location"`
+	// Time is when the entry was recorded.
+	Time time.Time `json:"time"`
+	// Value is the entry's value, rendered as a string (via its Stringer or
+	// ColorableStringer representation, if any).
+	Value string `json:"value,omitempty"`
 }
 func (p PartStatus) withState(state types.SpecState, failure types.SpecFailure) PartStatus {
 	switch state {
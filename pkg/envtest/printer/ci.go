@@ -41,8 +41,26 @@ func hasEnv(vars ...string) bool {
 // In particular:
 // - Prow = $CI and $ARTIFACTS and ($JUNIT_OUTPUT or $PROW_JOB_ID)
 // - Actions/JSON = $CI and $REMOTE_TEST_OUT_PORT
+// - GitHub Actions annotations = $GITHUB_ACTIONS
+// - TeamCity service messages = $TEAMCITY_VERSION
 func CIReporters(suiteName string) []ginkgo.Reporter {
 	reporters := []ginkgo.Reporter{NewlineReporter{}}
+
+	// GitHub Actions and TeamCity are both unambiguously identified by
+	// their own env vars, so these two are enabled independently of
+	// $CI -- unlike everything below, which shares $CI's more generic
+	// "are we in some CI system" signal and needs it to disambiguate.
+
+	// GitHub Actions gets its own annotated, collapsible log output.
+	if hasEnv("GITHUB_ACTIONS") {
+		reporters = append(reporters, NewGitHubActionsReporter(suiteName))
+	}
+
+	// TEAMCITY_VERSION is the env var TeamCity injects into build steps.
+	if hasEnv("TEAMCITY_VERSION") {
+		reporters = append(reporters, NewTeamCityReporter(suiteName))
+	}
+
 	onCI := hasEnv("CI")
 	if !onCI {
 		return reporters
@@ -53,7 +71,12 @@ func CIReporters(suiteName string) []ginkgo.Reporter {
 		reporters = append(reporters, NewProwReporter(suiteName))
 	}
 
-	
+	// JUNIT_REPORT_FILE gives a standards-compliant JUnit file without
+	// needing any of the Prow-specific env vars above.
+	if junitFile := os.Getenv("JUNIT_REPORT_FILE"); junitFile != "" {
+		reporters = append(reporters, NewJUnitReporter(suiteName, junitFile, JUnitReportConfig{}))
+	}
+
 	if remoteAddr := os.Getenv("REMOTE_TEST_OUT_ADDR"); remoteAddr != "" {
 		reporters = append(reporters, &RemoteJSONReporter{Addr: remoteAddr})
 	}
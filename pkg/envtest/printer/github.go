@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// GitHubActionsReporter emits GitHub Actions workflow commands to stdout as
+// specs complete: "::error ...::" annotations for failures/panics,
+// "::warning ...::" for skips with a reason, and a "::group::"/"::endgroup::"
+// pair around each top-level Describe/Context so the raw test log collapses
+// cleanly in the Actions UI. It finishes with a "::notice::" summarizing the
+// suite's final SuiteStats tally.
+//
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+// for the command syntax.
+type GitHubActionsReporter struct {
+	suiteName string
+
+	currentGroup string
+	groupOpen    bool
+}
+
+// NewGitHubActionsReporter builds a GitHubActionsReporter for the given
+// suite name.
+func NewGitHubActionsReporter(suiteName string) *GitHubActionsReporter {
+	return &GitHubActionsReporter{suiteName: suiteName}
+}
+
+func (r *GitHubActionsReporter) SpecSuiteWillBegin(conf config.GinkgoConfigType, summary *types.SuiteSummary) {
+	// No-op
+}
+
+func (r *GitHubActionsReporter) SpecWillRun(specSummary *types.SpecSummary) {
+	group := r.suiteName
+	if len(specSummary.ComponentTexts) > 1 {
+		group = specSummary.ComponentTexts[0]
+	}
+	if group == r.currentGroup {
+		return
+	}
+
+	r.closeGroup()
+	fmt.Printf("::group::%s\n", ghEscapeData(group))
+	r.currentGroup = group
+	r.groupOpen = true
+}
+
+func (r *GitHubActionsReporter) closeGroup() {
+	if !r.groupOpen {
+		return
+	}
+	fmt.Println("::endgroup::")
+	r.groupOpen = false
+}
+
+func (r *GitHubActionsReporter) BeforeSuiteDidRun(sum *types.SetupSummary) {
+	r.annotateSetup("BeforeSuite", sum)
+}
+
+func (r *GitHubActionsReporter) AfterSuiteDidRun(sum *types.SetupSummary) {
+	r.annotateSetup("AfterSuite", sum)
+}
+
+func (r *GitHubActionsReporter) annotateSetup(name string, sum *types.SetupSummary) {
+	if sum.State == types.SpecStatePassed {
+		return
+	}
+	loc := locToLoc(sum.CodeLocation)
+	fmt.Printf("::error file=%s,line=%d,title=%s::%s\n",
+		ghEscapeProperty(loc.File), loc.Line, ghEscapeProperty(name), ghEscapeData(sum.Failure.Message))
+}
+
+func (r *GitHubActionsReporter) SpecDidComplete(sum *types.SpecSummary) {
+	components := summaryToComponents(sum.ComponentTexts, sum.ComponentCodeLocations)
+	title := componentsToTitle(components)
+
+	switch sum.State {
+	case types.SpecStateFailed, types.SpecStatePanicked, types.SpecStateTimedOut:
+		loc := locToLoc(sum.Failure.Location)
+		fmt.Printf("::error file=%s,line=%d,title=%s::%s\n",
+			ghEscapeProperty(loc.File), loc.Line, ghEscapeProperty(title), ghEscapeData(sum.Failure.Message))
+	case types.SpecStateSkipped:
+		if sum.Failure.Message == "" {
+			return
+		}
+		loc := locToLoc(sum.Failure.Location)
+		fmt.Printf("::warning file=%s,line=%d,title=%s::%s\n",
+			ghEscapeProperty(loc.File), loc.Line, ghEscapeProperty(title), ghEscapeData(sum.Failure.Message))
+	}
+}
+
+func (r *GitHubActionsReporter) SpecSuiteDidEnd(sum *types.SuiteSummary) {
+	r.closeGroup()
+
+	fmt.Printf(
+		"::notice title=%s::%d specs, %d passed, %d failed, %d pending, %d skipped, %d flaked (%s)\n",
+		ghEscapeProperty(r.suiteName), sum.NumberOfTotalSpecs, sum.NumberOfPassedSpecs, sum.NumberOfFailedSpecs,
+		sum.NumberOfPendingSpecs, sum.NumberOfSkippedSpecs, sum.NumberOfFlakedSpecs, sum.RunTime,
+	)
+}
+
+// componentsToTitle renders a spec's path (Describe --> Context --> It) as
+// a single annotation title.
+func componentsToTitle(components []StatusComponent) string {
+	texts := make([]string, len(components))
+	for i, c := range components {
+		texts[i] = c.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// ghEscapeData escapes a string for use as a workflow command's value (the
+// part after the final "::"), per GitHub's documented command escaping.
+func ghEscapeData(s string) string {
+	replacer := strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	return replacer.Replace(s)
+}
+
+// ghEscapeProperty escapes a string for use as a workflow command property
+// value (e.g. file=..., title=...), which additionally requires escaping
+// ":" and "," since those delimit properties.
+func ghEscapeProperty(s string) string {
+	replacer := strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import "testing"
+
+func TestGhEscapeData(t *testing.T) {
+	cases := map[string]string{
+		"plain":          "plain",
+		"100% done":      "100%25 done",
+		"line1\nline2":   "line1%0Aline2",
+		"line1\r\nline2": "line1%0D%0Aline2",
+		"a: b, c":        "a: b, c",
+	}
+	for in, want := range cases {
+		if got := ghEscapeData(in); got != want {
+			t.Errorf("ghEscapeData(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGhEscapeProperty(t *testing.T) {
+	cases := map[string]string{
+		"plain":        "plain",
+		"a: b, c":      "a%3A b%2C c",
+		"100%":         "100%25",
+		"line1\nline2": "line1%0Aline2",
+	}
+	for in, want := range cases {
+		if got := ghEscapeProperty(in); got != want {
+			t.Errorf("ghEscapeProperty(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// JUnitReportConfig controls which (optional) pieces of detail
+// JUnitReporter includes in its output. The defaults (all false) produce
+// the most detailed report; each field trims one part of that detail,
+// which is occasionally necessary to satisfy a consumer's stricter
+// reading of the llg.cubic.org JUnit schema.
+type JUnitReportConfig struct {
+	// OmitTimelines omits each testcase's per-entry timeline
+	// (ReportEntries) from its <system-out>.
+	OmitTimelines bool
+	// OmitFailureMessageAttr omits the message attribute on <failure> and
+	// <error> elements, leaving the failure message only in the element
+	// body.
+	OmitFailureMessageAttr bool
+	// OmitCapturedStdOutErr omits the <system-out>/<system-err> elements
+	// entirely, dropping any captured output.
+	OmitCapturedStdOutErr bool
+	// OmitSpecLabels omits the <properties> block listing a testcase's
+	// labels.
+	OmitSpecLabels bool
+	// OmitLeafNodeType omits the "[<type>]" classname suffix naming the
+	// component type (e.g. It, BeforeEach) that a failed, panicked, or
+	// timed-out testcase failed in. Testcases without a failure never get
+	// that suffix.
+	OmitLeafNodeType bool
+	// OmitSuiteSetupNodes omits BeforeSuite/AfterSuite from the report
+	// entirely, reporting only the specs themselves.
+	OmitSuiteSetupNodes bool
+}
+
+// JUnitReporter is a Reporter that writes a JUnit XML report (the
+// testsuites/testsuite/testcase document used by the llg.cubic.org schema,
+// matching the one Ginkgo v2 writes natively) to a file, using the same
+// Suite/PartStatus data this package's RemoteJSONReporter sends over the
+// wire. Unlike NewProwReporter, it doesn't depend on any Prow-specific
+// environment variables.
+type JUnitReporter struct {
+	suiteName string
+	dst       string
+	config    JUnitReportConfig
+
+	suite Suite
+}
+
+// NewJUnitReporter builds a JUnitReporter that writes its report to dst
+// once the suite finishes running.
+func NewJUnitReporter(suiteName, dst string, config JUnitReportConfig) *JUnitReporter {
+	return &JUnitReporter{suiteName: suiteName, dst: dst, config: config}
+}
+
+func (r *JUnitReporter) SpecSuiteWillBegin(conf config.GinkgoConfigType, summary *types.SuiteSummary) {
+	r.suite.Name = summary.SuiteDescription
+	r.suite.ID = summary.SuiteID
+}
+
+func (r *JUnitReporter) SpecWillRun(specSummary *types.SpecSummary) {
+	// No-op
+}
+
+func (r *JUnitReporter) BeforeSuiteDidRun(sum *types.SetupSummary) {
+	if r.config.OmitSuiteSetupNodes {
+		return
+	}
+	stat := PartStatus{
+		Components: []StatusComponent{{Text: "BeforeSuite", Location: locToLoc(sum.CodeLocation)}},
+		RunTime:    sum.RunTime,
+		Output:     sum.CapturedOutput,
+	}.withState(sum.State, sum.Failure)
+	r.suite.BeforeSuite = &stat
+}
+
+func (r *JUnitReporter) AfterSuiteDidRun(sum *types.SetupSummary) {
+	if r.config.OmitSuiteSetupNodes {
+		return
+	}
+	stat := PartStatus{
+		Components: []StatusComponent{{Text: "AfterSuite", Location: locToLoc(sum.CodeLocation)}},
+		RunTime:    sum.RunTime,
+		Output:     sum.CapturedOutput,
+	}.withState(sum.State, sum.Failure)
+	r.suite.AfterSuite = &stat
+}
+
+func (r *JUnitReporter) SpecDidComplete(sum *types.SpecSummary) {
+	r.suite.MoreTestCases = append(r.suite.MoreTestCases, PartStatus{
+		Components: summaryToComponents(sum.ComponentTexts, sum.ComponentCodeLocations),
+		RunTime:    sum.RunTime,
+		Output:     sum.CapturedOutput,
+	}.withState(sum.State, sum.Failure))
+}
+
+func (r *JUnitReporter) SpecSuiteDidEnd(sum *types.SuiteSummary) {
+	r.suite.RunTime = sum.RunTime
+	r.suite.Stats = &SuiteStats{
+		Total:   sum.NumberOfTotalSpecs,
+		Pending: sum.NumberOfPendingSpecs,
+		Skipped: sum.NumberOfSkippedSpecs,
+		Passed:  sum.NumberOfPassedSpecs,
+		Failed:  sum.NumberOfFailedSpecs,
+		Flakes:  sum.NumberOfFlakedSpecs,
+	}
+
+	if err := r.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write JUnit report for suite %q to %q: %v\n", r.suite.Name, r.dst, err)
+	}
+}
+
+// write renders the accumulated suite as JUnit XML and saves it to r.dst.
+func (r *JUnitReporter) write() error {
+	doc := r.buildDocument()
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal JUnit report: %w", err)
+	}
+
+	return os.WriteFile(r.dst, append([]byte(xml.Header), data...), 0644) //nolint:gosec
+}
+
+// buildDocument converts the accumulated suite into the JUnit
+// testsuites/testsuite/testcase document shape.
+func (r *JUnitReporter) buildDocument() junitTestSuites {
+	cases := make([]junitTestCase, 0, len(r.suite.MoreTestCases)+2)
+	if r.suite.BeforeSuite != nil {
+		cases = append(cases, r.partToTestCase(*r.suite.BeforeSuite))
+	}
+	for _, part := range r.suite.MoreTestCases {
+		cases = append(cases, r.partToTestCase(part))
+	}
+	if r.suite.AfterSuite != nil {
+		cases = append(cases, r.partToTestCase(*r.suite.AfterSuite))
+	}
+
+	suite := junitTestSuite{
+		Name:      r.suiteName,
+		Time:      r.suite.RunTime.Seconds(),
+		TestCases: cases,
+	}
+	for _, c := range cases {
+		suite.Tests++
+		switch {
+		case c.Error != nil:
+			suite.Errors++
+		case c.Failure != nil:
+			suite.Failures++
+		case c.Skipped != nil:
+			suite.Disabled++
+		}
+	}
+
+	return junitTestSuites{TestSuites: []junitTestSuite{suite}}
+}
+
+// partToTestCase converts a single PartStatus (a Spec, BeforeSuite, or
+// AfterSuite result) into a JUnit testcase element.
+func (r *JUnitReporter) partToTestCase(part PartStatus) junitTestCase {
+	texts := make([]string, len(part.Components))
+	for i, c := range part.Components {
+		texts[i] = c.Text
+	}
+	name := strings.Join(texts, " ")
+
+	classname := r.suiteName
+	if !r.config.OmitLeafNodeType && part.Failure != nil {
+		classname = fmt.Sprintf("%s [%s]", classname, part.Failure.Component.Type)
+	}
+
+	tc := junitTestCase{
+		Name:      name,
+		Classname: classname,
+		Time:      part.RunTime.Seconds(),
+	}
+
+	if !r.config.OmitSpecLabels && len(part.Labels) > 0 {
+		props := make([]junitProperty, len(part.Labels))
+		for i, l := range part.Labels {
+			props[i] = junitProperty{Name: "label", Value: l}
+		}
+		tc.Properties = &junitProperties{Properties: props}
+	}
+
+	switch part.State {
+	case SpecStateSkipped, SpecStatePending:
+		tc.Skipped = &junitSkipped{}
+	case SpecStatePanicked, SpecStateTimedOut:
+		tc.Error = r.failureToResult(part.Failure)
+	case SpecStateFailed:
+		tc.Failure = r.failureToResult(part.Failure)
+	}
+
+	if !r.config.OmitCapturedStdOutErr {
+		tc.SystemOut = r.systemOut(part)
+	}
+
+	return tc
+}
+
+// failureToResult converts FailureInfo into the shared message/body pair
+// used by both <failure> and <error> elements.
+func (r *JUnitReporter) failureToResult(failure *FailureInfo) *junitResult {
+	if failure == nil {
+		return &junitResult{}
+	}
+	res := &junitResult{Description: failure.Message}
+	if !r.config.OmitFailureMessageAttr {
+		res.Message = failure.Message
+	}
+	return res
+}
+
+// systemOut renders a testcase's captured output, optionally followed by
+// its timeline (ReportEntries), as <system-out> text.
+func (r *JUnitReporter) systemOut(part PartStatus) string {
+	out := part.Output
+	if !r.config.OmitTimelines && len(part.ReportEntries) > 0 {
+		var b strings.Builder
+		b.WriteString(out)
+		for _, entry := range part.ReportEntries {
+			fmt.Fprintf(&b, "\n[%s] %s: %s", entry.Time.Format("15:04:05.000"), entry.Name, entry.Value)
+		}
+		out = b.String()
+	}
+	return out
+}
+
+// junitTestSuites is the document root: <testsuites>.
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a single <testsuite>.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Disabled  int             `xml:"disabled,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single <testcase>.
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	Time       float64          `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Skipped    *junitSkipped    `xml:"skipped,omitempty"`
+	Failure    *junitResult     `xml:"failure,omitempty"`
+	Error      *junitResult     `xml:"error,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+	SystemErr  string           `xml:"system-err,omitempty"`
+}
+
+// junitProperties is the <properties> child of a <testcase>.
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+// junitProperty is a single <property name="..." value="..."/>.
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitSkipped is an empty <skipped/> element.
+type junitSkipped struct{}
+
+// junitResult is the shared shape of <failure> and <error> elements:
+// an optional message attribute, plus the full description in the body.
+type junitResult struct {
+	Message     string `xml:"message,attr,omitempty"`
+	Description string `xml:",chardata"`
+}
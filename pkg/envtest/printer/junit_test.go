@@ -0,0 +1,227 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/onsi/ginkgo/types"
+)
+
+func partWithState(state SpecState) PartStatus {
+	p := PartStatus{
+		Components: []StatusComponent{{Text: "widget"}, {Text: "does a thing"}},
+		Output:     "some output",
+		Labels:     []string{"slow"},
+		ReportEntries: []ReportEntry{
+			{Name: "note", Value: "hello"},
+		},
+	}
+	p.State = state
+	if state == SpecStateFailed || state == SpecStatePanicked || state == SpecStateTimedOut {
+		p.Failure = &FailureInfo{
+			Message: "things went wrong",
+			Component: FailureComponent{
+				Type: ComponentTypeIt,
+			},
+		}
+	}
+	return p
+}
+
+func TestBuildDocumentTallies(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+	r.suite.MoreTestCases = []PartStatus{
+		partWithState(SpecStatePassed),
+		partWithState(SpecStateFailed),
+		partWithState(SpecStatePanicked),
+		partWithState(SpecStateTimedOut),
+		partWithState(SpecStateSkipped),
+		partWithState(SpecStatePending),
+	}
+
+	doc := r.buildDocument()
+	if len(doc.TestSuites) != 1 {
+		t.Fatalf("expected 1 testsuite, got %d", len(doc.TestSuites))
+	}
+	suite := doc.TestSuites[0]
+
+	if suite.Tests != 6 {
+		t.Errorf("Tests = %d, want 6", suite.Tests)
+	}
+	if suite.Failures != 1 {
+		t.Errorf("Failures = %d, want 1", suite.Failures)
+	}
+	if suite.Errors != 2 {
+		t.Errorf("Errors = %d, want 2 (panicked + timed-out)", suite.Errors)
+	}
+	if suite.Disabled != 2 {
+		t.Errorf("Disabled = %d, want 2 (skipped + pending)", suite.Disabled)
+	}
+}
+
+func TestBuildDocumentIncludesSuiteSetupNodes(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+	before := partWithState(SpecStatePassed)
+	after := partWithState(SpecStateFailed)
+	r.suite.BeforeSuite = &before
+	r.suite.AfterSuite = &after
+	r.suite.MoreTestCases = []PartStatus{partWithState(SpecStatePassed)}
+
+	doc := r.buildDocument()
+	suite := doc.TestSuites[0]
+	if suite.Tests != 3 {
+		t.Fatalf("Tests = %d, want 3 (BeforeSuite + 1 spec + AfterSuite)", suite.Tests)
+	}
+	if suite.TestCases[0].Name == "" || suite.TestCases[len(suite.TestCases)-1].Name == "" {
+		t.Fatalf("expected BeforeSuite/AfterSuite testcases to be present with names")
+	}
+}
+
+func TestOmitSuiteSetupNodes(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{OmitSuiteSetupNodes: true})
+	sum := &types.SetupSummary{State: types.SpecStatePassed}
+
+	r.BeforeSuiteDidRun(sum)
+	r.AfterSuiteDidRun(sum)
+
+	if r.suite.BeforeSuite != nil || r.suite.AfterSuite != nil {
+		t.Fatalf("expected BeforeSuite/AfterSuite to be omitted")
+	}
+}
+
+func TestPartToTestCaseStates(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+
+	cases := []struct {
+		state       SpecState
+		wantSkipped bool
+		wantFailure bool
+		wantError   bool
+	}{
+		{SpecStatePassed, false, false, false},
+		{SpecStateSkipped, true, false, false},
+		{SpecStatePending, true, false, false},
+		{SpecStateFailed, false, true, false},
+		{SpecStatePanicked, false, false, true},
+		{SpecStateTimedOut, false, false, true},
+	}
+
+	for _, c := range cases {
+		tc := r.partToTestCase(partWithState(c.state))
+		if (tc.Skipped != nil) != c.wantSkipped {
+			t.Errorf("state %v: Skipped set = %v, want %v", c.state, tc.Skipped != nil, c.wantSkipped)
+		}
+		if (tc.Failure != nil) != c.wantFailure {
+			t.Errorf("state %v: Failure set = %v, want %v", c.state, tc.Failure != nil, c.wantFailure)
+		}
+		if (tc.Error != nil) != c.wantError {
+			t.Errorf("state %v: Error set = %v, want %v", c.state, tc.Error != nil, c.wantError)
+		}
+	}
+}
+
+func TestPartToTestCaseOmitFlags(t *testing.T) {
+	full := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+	part := partWithState(SpecStateFailed)
+
+	tc := full.partToTestCase(part)
+	if tc.Properties == nil {
+		t.Errorf("expected Properties to be set by default")
+	}
+	if tc.Failure == nil || tc.Failure.Message == "" {
+		t.Errorf("expected Failure.Message to be set by default")
+	}
+	if tc.SystemOut == "" {
+		t.Errorf("expected SystemOut to be set by default")
+	}
+	if tc.Classname == full.suiteName {
+		t.Errorf("expected classname to include leaf node type suffix by default")
+	}
+
+	omitAll := NewJUnitReporter("my-suite", "", JUnitReportConfig{
+		OmitSpecLabels:         true,
+		OmitFailureMessageAttr: true,
+		OmitCapturedStdOutErr:  true,
+		OmitLeafNodeType:       true,
+	})
+	tc = omitAll.partToTestCase(part)
+	if tc.Properties != nil {
+		t.Errorf("OmitSpecLabels: expected Properties to be omitted")
+	}
+	if tc.Failure == nil || tc.Failure.Message != "" {
+		t.Errorf("OmitFailureMessageAttr: expected Failure.Message to be empty")
+	}
+	if tc.Failure == nil || tc.Failure.Description == "" {
+		t.Errorf("OmitFailureMessageAttr: expected Failure.Description to still carry the message")
+	}
+	if tc.SystemOut != "" {
+		t.Errorf("OmitCapturedStdOutErr: expected SystemOut to be omitted")
+	}
+	if tc.Classname != omitAll.suiteName {
+		t.Errorf("OmitLeafNodeType: expected classname %q, got %q", omitAll.suiteName, tc.Classname)
+	}
+}
+
+func TestSystemOutOmitTimelines(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+	part := partWithState(SpecStatePassed)
+
+	out := r.systemOut(part)
+	if out == part.Output {
+		t.Errorf("expected systemOut to append the timeline by default")
+	}
+
+	r.config.OmitTimelines = true
+	out = r.systemOut(part)
+	if out != part.Output {
+		t.Errorf("OmitTimelines: expected systemOut to be just the captured output, got %q", out)
+	}
+}
+
+// TestDocumentRoundTrips confirms buildDocument's output actually
+// marshals/unmarshals as the JUnit XML shape consumers expect.
+func TestDocumentRoundTrips(t *testing.T) {
+	r := NewJUnitReporter("my-suite", "", JUnitReportConfig{})
+	r.suite.MoreTestCases = []PartStatus{
+		partWithState(SpecStatePassed),
+		partWithState(SpecStateFailed),
+	}
+
+	doc := r.buildDocument()
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unable to marshal document: %v", err)
+	}
+
+	var roundTripped junitTestSuites
+	if err := xml.Unmarshal(data, &roundTripped); err != nil {
+		t.Fatalf("unable to unmarshal document: %v", err)
+	}
+
+	if len(roundTripped.TestSuites) != 1 {
+		t.Fatalf("expected 1 testsuite after round-trip, got %d", len(roundTripped.TestSuites))
+	}
+	gotSuite := roundTripped.TestSuites[0]
+	if gotSuite.Tests != 2 || gotSuite.Failures != 1 {
+		t.Errorf("unexpected tallies after round-trip: %+v", gotSuite)
+	}
+	if len(gotSuite.TestCases) != 2 || gotSuite.TestCases[1].Failure == nil {
+		t.Errorf("expected second testcase to carry its failure after round-trip")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package remotesrv implements the receiver side of the wire protocol
+// spoken by printer.RemoteJSONReporter: an http.Handler that accepts
+// SuiteMessage POSTs at /report-suite, aggregates them by suite ID, streams
+// individual spec results to OnSpec as they arrive, and hands back the
+// final state of each suite to OnSuiteEnd once it finishes.
+package remotesrv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+)
+
+// Server aggregates incoming SuiteMessages by suite ID. It's safe for
+// concurrent use, since a single Server may be receiving updates from
+// several suites (e.g. parallel Ginkgo processes) at once.
+type Server struct {
+	// OnSpec, if set, is called once for each spec result as it arrives
+	// (i.e. as soon as the message carrying it is processed), so a
+	// consumer can report live progress instead of waiting for the suite
+	// to finish.
+	OnSpec func(suite *printer.Suite, spec printer.PartStatus)
+	// OnSuiteEnd, if set, is called with a snapshot of a suite's final
+	// state whenever that suite reports its "suite-end" action.
+	OnSuiteEnd func(suite *printer.Suite)
+
+	mu     sync.Mutex
+	suites map[string]*printer.Suite
+}
+
+// NewServer builds an empty Server.
+func NewServer() *Server {
+	return &Server{suites: map[string]*printer.Suite{}}
+}
+
+// ServeHTTP implements http.Handler, accepting SuiteMessage POSTs at
+// /report-suite.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/report-suite" || req.Method != http.MethodPost {
+		http.NotFound(w, req)
+		return
+	}
+
+	var msg printer.SuiteMessage
+	if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+		http.Error(w, fmt.Sprintf("invalid suite message: %v", err), http.StatusBadRequest)
+		return
+	}
+	if msg.Suite == nil || msg.Suite.ID == "" {
+		http.Error(w, "suite message missing suite ID", http.StatusBadRequest)
+		return
+	}
+
+	s.apply(msg)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// apply merges msg into the aggregated state for its suite, then -- once
+// the lock is released -- fires OnSpec for each newly-arrived spec and
+// OnSuiteEnd if msg was a suite-end message.
+func (s *Server) apply(msg printer.SuiteMessage) {
+	s.mu.Lock()
+	suite, ok := s.suites[msg.Suite.ID]
+	if !ok {
+		suite = &printer.Suite{ID: msg.Suite.ID}
+		s.suites[msg.Suite.ID] = suite
+	}
+
+	suite.Name = msg.Suite.Name
+	if msg.Suite.RunTime != 0 {
+		suite.RunTime = msg.Suite.RunTime
+	}
+	if msg.Suite.BeforeSuite != nil {
+		suite.BeforeSuite = msg.Suite.BeforeSuite
+	}
+	if msg.Suite.AfterSuite != nil {
+		suite.AfterSuite = msg.Suite.AfterSuite
+	}
+	suite.MoreTestCases = append(suite.MoreTestCases, msg.Suite.MoreTestCases...)
+	if msg.Suite.Stats != nil {
+		suite.Stats = msg.Suite.Stats
+	}
+	if len(msg.Suite.Labels) > 0 {
+		suite.Labels = msg.Suite.Labels
+	}
+	if len(msg.Suite.SpecialFailureReasons) > 0 {
+		suite.SpecialFailureReasons = msg.Suite.SpecialFailureReasons
+	}
+
+	newSpecs := msg.Suite.MoreTestCases
+	var ended *printer.Suite
+	if msg.Action == printer.ActionSuiteEnd {
+		snapshot := *suite
+		ended = &snapshot
+	}
+	snapshot := *suite
+	s.mu.Unlock()
+
+	if s.OnSpec != nil {
+		for _, spec := range newSpecs {
+			s.OnSpec(&snapshot, spec)
+		}
+	}
+	if ended != nil && s.OnSuiteEnd != nil {
+		s.OnSuiteEnd(ended)
+	}
+}
+
+// Suite returns a snapshot of the current aggregated state for the suite
+// with the given ID, or nil if no messages have been received for it yet.
+func (s *Server) Suite(id string) *printer.Suite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suite, ok := s.suites[id]
+	if !ok {
+		return nil
+	}
+	snapshot := *suite
+	return &snapshot
+}
+
+// ListenAndServe starts an HTTP server on addr backed by srv, blocking
+// until it exits.
+func ListenAndServe(addr string, srv *Server) error {
+	return http.ListenAndServe(addr, srv) //nolint:gosec
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotesrv
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+)
+
+func post(t *testing.T, handler http.Handler, msg printer.SuiteMessage) *httptest.ResponseRecorder {
+	t.Helper()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("unable to marshal message: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/report-suite", bytes.NewReader(data))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerAggregatesBySuiteID(t *testing.T) {
+	var ended *printer.Suite
+	srv := NewServer()
+	srv.OnSuiteEnd = func(suite *printer.Suite) { ended = suite }
+
+	rec := post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteStart,
+		Suite:  &printer.Suite{ID: "suite-1", Name: "My Suite"},
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("suite-start: got status %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	rec = post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteUpdate,
+		Suite: &printer.Suite{ID: "suite-1", Name: "My Suite", MoreTestCases: []printer.PartStatus{
+			{Output: "first"},
+		}},
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("suite-update 1: got status %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	rec = post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteUpdate,
+		Suite: &printer.Suite{ID: "suite-1", Name: "My Suite", MoreTestCases: []printer.PartStatus{
+			{Output: "second"},
+		}},
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("suite-update 2: got status %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	if ended != nil {
+		t.Fatalf("OnSuiteEnd fired before suite-end: %+v", ended)
+	}
+
+	stats := &printer.SuiteStats{Total: 2, Passed: 2}
+	rec = post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteEnd,
+		Suite:  &printer.Suite{ID: "suite-1", Name: "My Suite", Stats: stats},
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("suite-end: got status %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	if ended == nil {
+		t.Fatalf("expected OnSuiteEnd to fire on suite-end")
+	}
+	if len(ended.MoreTestCases) != 2 {
+		t.Fatalf("expected 2 aggregated test cases, got %d", len(ended.MoreTestCases))
+	}
+	if ended.MoreTestCases[0].Output != "first" || ended.MoreTestCases[1].Output != "second" {
+		t.Fatalf("expected test cases in arrival order, got %+v", ended.MoreTestCases)
+	}
+	if ended.Stats == nil || ended.Stats.Total != 2 {
+		t.Fatalf("expected suite-end stats to be applied, got %+v", ended.Stats)
+	}
+
+	snapshot := srv.Suite("suite-1")
+	if snapshot == nil || len(snapshot.MoreTestCases) != 2 {
+		t.Fatalf("expected Suite() to return the same aggregated state, got %+v", snapshot)
+	}
+}
+
+func TestServerKeepsSuitesSeparate(t *testing.T) {
+	srv := NewServer()
+
+	post(t, srv, printer.SuiteMessage{Action: printer.ActionSuiteStart, Suite: &printer.Suite{ID: "a", Name: "Suite A"}})
+	post(t, srv, printer.SuiteMessage{Action: printer.ActionSuiteStart, Suite: &printer.Suite{ID: "b", Name: "Suite B"}})
+
+	a := srv.Suite("a")
+	b := srv.Suite("b")
+	if a == nil || a.Name != "Suite A" {
+		t.Fatalf("expected suite a to be tracked separately, got %+v", a)
+	}
+	if b == nil || b.Name != "Suite B" {
+		t.Fatalf("expected suite b to be tracked separately, got %+v", b)
+	}
+}
+
+func TestServerRejectsMissingSuiteID(t *testing.T) {
+	srv := NewServer()
+	rec := post(t, srv, printer.SuiteMessage{Action: printer.ActionSuiteStart, Suite: &printer.Suite{}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerRejectsNonPostRequest(t *testing.T) {
+	srv := NewServer()
+	req := httptest.NewRequest(http.MethodGet, "/report-suite", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /report-suite: got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestServerUnknownSuiteReturnsNil(t *testing.T) {
+	srv := NewServer()
+	if srv.Suite("nonexistent") != nil {
+		t.Fatalf("expected nil for an unknown suite ID")
+	}
+}
+
+func TestServerOnSpecFiresPerSpecAsTheyArrive(t *testing.T) {
+	var seen []string
+	srv := NewServer()
+	srv.OnSpec = func(suite *printer.Suite, spec printer.PartStatus) {
+		seen = append(seen, spec.Output)
+	}
+
+	post(t, srv, printer.SuiteMessage{Action: printer.ActionSuiteStart, Suite: &printer.Suite{ID: "suite-1"}})
+	if len(seen) != 0 {
+		t.Fatalf("expected no OnSpec calls from suite-start, got %v", seen)
+	}
+
+	post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteUpdate,
+		Suite: &printer.Suite{ID: "suite-1", MoreTestCases: []printer.PartStatus{
+			{Output: "first"}, {Output: "second"},
+		}},
+	})
+	if len(seen) != 2 || seen[0] != "first" || seen[1] != "second" {
+		t.Fatalf("expected OnSpec to fire once per new spec in order, got %v", seen)
+	}
+
+	post(t, srv, printer.SuiteMessage{
+		Action: printer.ActionSuiteUpdate,
+		Suite: &printer.Suite{ID: "suite-1", MoreTestCases: []printer.PartStatus{
+			{Output: "third"},
+		}},
+	})
+	if len(seen) != 3 || seen[2] != "third" {
+		t.Fatalf("expected OnSpec to fire only for the newly-arrived spec, got %v", seen)
+	}
+}
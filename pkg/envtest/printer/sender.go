@@ -0,0 +1,324 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBufferSize bounds how many not-yet-acknowledged spec updates
+	// a Sender keeps around for replay after a reconnect, if BufferSize
+	// isn't set.
+	defaultBufferSize = 256
+
+	// initialBackoff and maxBackoff bound the exponential backoff a Sender
+	// uses between retries of a failed send.
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff      = 30 * time.Second
+
+	// endFlushTimeout is how long EndSuite will wait for the final
+	// suite-end message to be delivered before giving up and returning
+	// anyway.
+	endFlushTimeout = 30 * time.Second
+)
+
+// Sender is the transport-agnostic machinery shared by RemoteJSONReporter
+// and its Ginkgo v2 counterpart (printer/v2): it accumulates suite state,
+// buffers spec updates in a bounded ring buffer, and flushes them to a
+// pluggable Transport on a background goroutine with exponential backoff,
+// so the reporting callback that feeds it never blocks on the network.
+//
+// A Sender is driven by its Begin*/Set*/Push*/End* methods, which both
+// RemoteJSONReporter (from v1 Reporter callbacks) and printer/v2's
+// reporter (from Ginkgo v2 Report callbacks) call after translating their
+// respective data models into the shared Suite/PartStatus wire types.
+type Sender struct {
+	Addr string
+
+	// Transport overrides how messages are sent. If nil, one is built from
+	// Addr and the REMOTE_TEST_OUT_TRANSPORT, REMOTE_TEST_OUT_SCHEME, and
+	// REMOTE_TEST_OUT_TOKEN environment variables -- see NewTransport.
+	Transport Transport
+	// BufferSize bounds how many not-yet-acknowledged spec updates are
+	// buffered for replay after a reconnect. Defaults to
+	// defaultBufferSize.
+	BufferSize int
+
+	startOnce sync.Once
+
+	mu         sync.Mutex
+	suite      Suite
+	buf        *ringBuffer
+	action     SuiteAction
+	lastUpdate time.Time
+
+	wake  chan struct{}
+	acked chan struct{}
+}
+
+// Start lazily builds the transport and ring buffer and launches the
+// background sender goroutine. It's safe to call repeatedly, and is
+// called automatically by the Begin*/Push* methods.
+func (s *Sender) Start() {
+	s.startOnce.Do(func() {
+		if s.Transport == nil {
+			t, err := NewTransport(s.Addr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "unable to build transport for suite %q, falling back to plain HTTP: %v\n", s.suite.Name, err)
+				t = newHTTPTransport(fmt.Sprintf("http://%s/report-suite", s.Addr), "", http.DefaultClient)
+			}
+			s.Transport = t
+		}
+		if s.BufferSize <= 0 {
+			s.BufferSize = defaultBufferSize
+		}
+		s.buf = newRingBuffer(s.BufferSize)
+		s.wake = make(chan struct{}, 1)
+		s.acked = make(chan struct{})
+
+		go s.run()
+	})
+}
+
+// BeginSuite records the suite's identity and (Ginkgo v2 only) labels, and
+// sends the suite-start message.
+func (s *Sender) BeginSuite(name, id string, labels []string) {
+	s.Start()
+
+	s.mu.Lock()
+	s.suite.Name = name
+	s.suite.ID = id
+	s.suite.Labels = labels
+	s.mu.Unlock()
+
+	s.signal(ActionSuiteStart)
+}
+
+// SetBeforeSuite records the result of the suite's BeforeSuite node.
+func (s *Sender) SetBeforeSuite(p PartStatus) {
+	s.Start()
+
+	s.mu.Lock()
+	s.suite.BeforeSuite = &p
+	s.mu.Unlock()
+
+	s.signal(ActionSuiteUpdate)
+}
+
+// SetAfterSuite records the result of the suite's AfterSuite node.
+func (s *Sender) SetAfterSuite(p PartStatus) {
+	s.Start()
+
+	s.mu.Lock()
+	s.suite.AfterSuite = &p
+	s.mu.Unlock()
+
+	s.signal(ActionSuiteUpdate)
+}
+
+// PushSpec buffers a single completed spec's result for sending.
+func (s *Sender) PushSpec(p PartStatus) {
+	s.Start()
+
+	s.mu.Lock()
+	s.buf.Push(p)
+	s.mu.Unlock()
+
+	s.signal(ActionSuiteUpdate)
+}
+
+// EndSuite records the suite's final tallies, sends the suite-end message,
+// and blocks until it's been flushed (or endFlushTimeout elapses).
+func (s *Sender) EndSuite(runTime time.Duration, stats *SuiteStats, specialFailureReasons []string) {
+	s.Start()
+
+	s.mu.Lock()
+	s.suite.RunTime = runTime
+	s.suite.Stats = stats
+	s.suite.SpecialFailureReasons = specialFailureReasons
+	s.mu.Unlock()
+
+	s.signal(ActionSuiteEnd)
+
+	select {
+	case <-s.acked:
+	case <-time.After(endFlushTimeout):
+		fmt.Fprintf(os.Stderr, "timed out waiting to flush suite-end report for suite %q\n", s.suite.Name)
+	}
+}
+
+// signal records that action (or a more important one already pending)
+// should be sent, and wakes the background sender if it's idle.
+func (s *Sender) signal(action SuiteAction) {
+	s.mu.Lock()
+	if actionRank(action) > actionRank(s.action) {
+		s.action = action
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// actionRank orders actions by importance, so a pending ActionSuiteEnd is
+// never downgraded back to ActionSuiteUpdate by a later signal.
+func actionRank(action SuiteAction) int {
+	switch action {
+	case ActionSuiteEnd:
+		return 2
+	case ActionSuiteUpdate:
+		return 1
+	case ActionSuiteStart:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// run is the background sender: it wakes up whenever there's something new
+// to send, and retries with exponential backoff until the transport
+// accepts it, buffering any updates that arrive in the meantime.
+func (s *Sender) run() {
+	backoff := initialBackoff
+	for range s.wake {
+		for {
+			s.mu.Lock()
+			action := s.action
+			items, maxSeq := s.buf.Snapshot()
+			msg := SuiteMessage{
+				Action: action,
+				Suite: &Suite{
+					Name:                  s.suite.Name,
+					ID:                    s.suite.ID,
+					RunTime:               s.suite.RunTime,
+					BeforeSuite:           s.suite.BeforeSuite,
+					AfterSuite:            s.suite.AfterSuite,
+					MoreTestCases:         items,
+					Stats:                 s.suite.Stats,
+					Labels:                s.suite.Labels,
+					SpecialFailureReasons: s.suite.SpecialFailureReasons,
+				},
+			}
+			s.mu.Unlock()
+
+			if action == ActionSuiteUpdate && time.Since(s.lastUpdate) < updateThreshold && len(items) == 0 {
+				break
+			}
+
+			if err := s.Transport.Send(msg); err != nil {
+				fmt.Fprintf(os.Stderr, "unable to send %s for suite %q: %v; retrying in %s\n", action, s.suite.Name, err, backoff)
+				time.Sleep(backoff)
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+
+			backoff = initialBackoff
+			s.lastUpdate = time.Now()
+
+			s.mu.Lock()
+			s.buf.Evict(maxSeq)
+			done := s.action == action
+			if done {
+				s.action = ActionSuiteUpdate
+			}
+			s.mu.Unlock()
+
+			if action == ActionSuiteEnd {
+				if err := s.Transport.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "unable to close transport for suite %q: %v\n", s.suite.Name, err)
+				}
+				close(s.acked)
+				return
+			}
+			if done {
+				break
+			}
+		}
+	}
+}
+
+// ringBuffer is a fixed-capacity FIFO queue of buffered spec updates, each
+// tagged with a monotonically increasing sequence number. When full, Push
+// drops the oldest entry to make room for the new one, so a slow or
+// unavailable server can never grow a Sender's memory use unbounded.
+//
+// Entries are removed by Evict based on their sequence number rather than
+// their position in the slice, so a send that was snapshotted under the
+// lock and then took a while (backoff, retries) can still be reconciled
+// correctly against a buffer that kept growing -- and potentially
+// evicting from the front -- while the lock was released.
+type ringBuffer struct {
+	items   []bufferedPart
+	cap     int
+	nextSeq uint64
+}
+
+// bufferedPart is a single buffered spec update, tagged with the sequence
+// number it was assigned when pushed.
+type bufferedPart struct {
+	seq    uint64
+	status PartStatus
+}
+
+func newRingBuffer(cap int) *ringBuffer {
+	return &ringBuffer{cap: cap}
+}
+
+// Push must be called with the owning Sender's mu held.
+func (b *ringBuffer) Push(p PartStatus) {
+	b.nextSeq++
+	b.items = append(b.items, bufferedPart{seq: b.nextSeq, status: p})
+	if len(b.items) > b.cap {
+		b.items = b.items[len(b.items)-b.cap:]
+	}
+}
+
+// Snapshot returns a copy of the currently-buffered updates, plus the
+// highest sequence number among them (0 if empty), for later use with
+// Evict. Must be called with the owning Sender's mu held.
+func (b *ringBuffer) Snapshot() ([]PartStatus, uint64) {
+	if len(b.items) == 0 {
+		return nil, 0
+	}
+	statuses := make([]PartStatus, len(b.items))
+	for i, item := range b.items {
+		statuses[i] = item.status
+	}
+	return statuses, b.items[len(b.items)-1].seq
+}
+
+// Evict removes every buffered entry with a sequence number <= maxSeq --
+// i.e. the entries a prior Snapshot covered -- regardless of whether
+// they're still at the front of the buffer. Must be called with the
+// owning Sender's mu held.
+func (b *ringBuffer) Evict(maxSeq uint64) {
+	i := 0
+	for i < len(b.items) && b.items[i].seq <= maxSeq {
+		i++
+	}
+	b.items = b.items[i:]
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport is a stub Transport for exercising Sender.run without a real
+// network: Send fails the first failSends times (recording every action it
+// was asked to send), then starts succeeding.
+type fakeTransport struct {
+	mu        sync.Mutex
+	failSends int
+	sent      []SuiteAction
+	closed    bool
+}
+
+func (f *fakeTransport) Send(msg SuiteMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, msg.Action)
+	if f.failSends > 0 {
+		f.failSends--
+		return fmt.Errorf("simulated send failure")
+	}
+	return nil
+}
+
+func (f *fakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeTransport) actions() []SuiteAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SuiteAction(nil), f.sent...)
+}
+
+func TestRingBufferPushEvictsOldestOnceFull(t *testing.T) {
+	b := newRingBuffer(2)
+	b.Push(PartStatus{Output: "a"})
+	b.Push(PartStatus{Output: "b"})
+	b.Push(PartStatus{Output: "c"})
+
+	items, maxSeq := b.Snapshot()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 buffered items, got %d", len(items))
+	}
+	if items[0].Output != "b" || items[1].Output != "c" {
+		t.Fatalf("expected [b c], got %v", items)
+	}
+	if maxSeq != 3 {
+		t.Fatalf("expected maxSeq 3, got %d", maxSeq)
+	}
+}
+
+func TestRingBufferEvictIsIdentityBased(t *testing.T) {
+	// Regression test: Evict must remove entries by sequence number, not
+	// by position, so a snapshot taken before a concurrent Push can still
+	// be evicted correctly afterwards -- even if that Push displaced
+	// earlier entries out the front of an at-capacity buffer.
+	b := newRingBuffer(2)
+	b.Push(PartStatus{Output: "a"})
+	b.Push(PartStatus{Output: "b"})
+
+	items, maxSeq := b.Snapshot()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 buffered items, got %d", len(items))
+	}
+
+	// Simulate a spec completing (and evicting "a") while the send of the
+	// above snapshot was in flight.
+	b.Push(PartStatus{Output: "c"})
+
+	b.Evict(maxSeq)
+
+	items, _ = b.Snapshot()
+	if len(items) != 1 || items[0].Output != "c" {
+		t.Fatalf("expected only unsent item [c] to remain, got %v", items)
+	}
+}
+
+func TestRingBufferEvictIgnoresAlreadyEvicted(t *testing.T) {
+	b := newRingBuffer(2)
+	b.Push(PartStatus{Output: "a"})
+	_, maxSeq := b.Snapshot()
+
+	b.Evict(maxSeq)
+	b.Evict(maxSeq) // must not panic or evict anything further
+
+	items, _ := b.Snapshot()
+	if len(items) != 0 {
+		t.Fatalf("expected empty buffer, got %v", items)
+	}
+}
+
+func TestActionRankNeverDowngradesSuiteEnd(t *testing.T) {
+	if actionRank(ActionSuiteEnd) <= actionRank(ActionSuiteUpdate) {
+		t.Fatalf("ActionSuiteEnd must outrank ActionSuiteUpdate")
+	}
+	if actionRank(ActionSuiteUpdate) <= actionRank(ActionSuiteStart) {
+		t.Fatalf("ActionSuiteUpdate must outrank ActionSuiteStart")
+	}
+}
+
+// TestSenderRunRetriesWithBackoffUntilDelivered covers the end-to-end
+// retry/backoff loop in Sender.run: a transport that fails a few times in a
+// row must still eventually see the suite-end message delivered, rather than
+// Sender giving up.
+func TestSenderRunRetriesWithBackoffUntilDelivered(t *testing.T) {
+	ft := &fakeTransport{failSends: 3}
+	s := &Sender{Transport: ft}
+
+	s.BeginSuite("my-suite", "id-1", nil)
+	s.EndSuite(time.Second, &SuiteStats{Total: 1, Passed: 1}, nil)
+
+	actions := ft.actions()
+	if len(actions) < 4 {
+		t.Fatalf("expected at least 4 Send attempts (3 failures + 1 success), got %d: %v", len(actions), actions)
+	}
+	last := actions[len(actions)-1]
+	if last != ActionSuiteEnd {
+		t.Fatalf("expected the final delivered action to be suite-end, got %v", last)
+	}
+	if !ft.closed {
+		t.Fatalf("expected the transport to be closed after a successful suite-end send")
+	}
+}
+
+// TestSenderSignalNeverDowngradesPendingSuiteEnd covers signal's use of
+// actionRank end-to-end: once something has signaled ActionSuiteEnd, a
+// PushSpec racing in behind it (which signals ActionSuiteUpdate) must not
+// downgrade the pending action run is about to send.
+func TestSenderSignalNeverDowngradesPendingSuiteEnd(t *testing.T) {
+	s := &Sender{buf: newRingBuffer(defaultBufferSize), wake: make(chan struct{}, 1)}
+
+	s.signal(ActionSuiteEnd)
+	s.signal(ActionSuiteUpdate)
+
+	s.mu.Lock()
+	action := s.action
+	s.mu.Unlock()
+
+	if action != ActionSuiteEnd {
+		t.Fatalf("expected a later ActionSuiteUpdate signal not to downgrade a pending ActionSuiteEnd, got %v", action)
+	}
+}
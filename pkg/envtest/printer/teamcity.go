@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+// TeamCityReporter emits TeamCity service messages to stdout as specs
+// complete, following the same on-stdout, env-var-driven pattern as
+// NewProwReporter. It lets controller-runtime-based operators running
+// under TeamCity get first-class test reporting without a separate
+// xml-parsing build step.
+//
+// See https://www.jetbrains.com/help/teamcity/service-messages.html for
+// the message format.
+type TeamCityReporter struct {
+	suiteName string
+}
+
+// NewTeamCityReporter builds a TeamCityReporter for the given suite name.
+func NewTeamCityReporter(suiteName string) *TeamCityReporter {
+	return &TeamCityReporter{suiteName: suiteName}
+}
+
+func (r *TeamCityReporter) SpecSuiteWillBegin(conf config.GinkgoConfigType, summary *types.SuiteSummary) {
+	fmt.Printf("##teamcity[testSuiteStarted name='%s']\n", tcEscape(r.suiteName))
+}
+
+func (r *TeamCityReporter) SpecWillRun(specSummary *types.SpecSummary) {
+	fmt.Printf("##teamcity[testStarted name='%s']\n", tcEscape(specName(specSummary.ComponentTexts)))
+}
+
+func (r *TeamCityReporter) BeforeSuiteDidRun(sum *types.SetupSummary) {
+	// No-op
+}
+
+func (r *TeamCityReporter) AfterSuiteDidRun(sum *types.SetupSummary) {
+	// No-op
+}
+
+func (r *TeamCityReporter) SpecDidComplete(sum *types.SpecSummary) {
+	name := tcEscape(specName(sum.ComponentTexts))
+
+	switch sum.State {
+	case types.SpecStateFailed, types.SpecStatePanicked, types.SpecStateTimedOut:
+		fmt.Printf("##teamcity[testFailed name='%s' message='%s' details='%s']\n",
+			name, tcEscape(sum.Failure.Message), tcEscape(sum.Failure.ComponentCodeLocation.String()))
+	case types.SpecStateSkipped, types.SpecStatePending:
+		fmt.Printf("##teamcity[testIgnored name='%s']\n", name)
+	}
+
+	fmt.Printf("##teamcity[testFinished name='%s' duration='%d']\n", name, sum.RunTime.Milliseconds())
+}
+
+func (r *TeamCityReporter) SpecSuiteDidEnd(sum *types.SuiteSummary) {
+	fmt.Printf("##teamcity[testSuiteFinished name='%s']\n", tcEscape(r.suiteName))
+}
+
+// specName renders a spec's path (Describe --> Context --> It) as a single
+// TeamCity test name.
+func specName(componentTexts []string) string {
+	return strings.Join(componentTexts, " ")
+}
+
+// tcEscape escapes a string for use inside a single-quoted TeamCity
+// service message value.
+func tcEscape(s string) string {
+	replacer := strings.NewReplacer(
+		"|", "||",
+		"'", "|'",
+		"\n", "|n",
+		"\r", "|r",
+		"[", "|[",
+		"]", "|]",
+	)
+	return replacer.Replace(s)
+}
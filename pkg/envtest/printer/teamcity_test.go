@@ -0,0 +1,43 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import "testing"
+
+func TestTcEscape(t *testing.T) {
+	cases := map[string]string{
+		"plain":          "plain",
+		"a|b":            "a||b",
+		"it's":           "it|'s",
+		"line1\nline2":   "line1|nline2",
+		"line1\r\nline2": "line1|r|nline2",
+		"[tag]":          "|[tag|]",
+	}
+	for in, want := range cases {
+		if got := tcEscape(in); got != want {
+			t.Errorf("tcEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSpecName(t *testing.T) {
+	got := specName([]string{"Widget", "when empty", "returns zero"})
+	want := "Widget when empty returns zero"
+	if got != want {
+		t.Errorf("specName(...) = %q, want %q", got, want)
+	}
+}
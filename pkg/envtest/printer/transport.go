@@ -0,0 +1,310 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport is how RemoteJSONReporter actually gets a SuiteMessage to a
+// remote server. NewTransport builds the implementation appropriate for
+// the current environment; tests and unusual setups can instead set
+// RemoteJSONReporter.Transport directly.
+type Transport interface {
+	// Send delivers msg to the remote server, returning an error if it
+	// wasn't accepted. RemoteJSONReporter retries failed sends with
+	// backoff, so implementations don't need to retry internally.
+	Send(msg SuiteMessage) error
+
+	// Close releases any resources (connections, goroutines) held by the
+	// transport.
+	Close() error
+}
+
+// NewTransport builds the Transport appropriate for addr and the current
+// environment:
+//
+//   - REMOTE_TEST_OUT_TRANSPORT selects the wire format: "http" (the
+//     default) for one POST per message, "ndjson" for a single streamed
+//     connection with one JSON object per line, or "websocket" for a
+//     WebSocket connection to /report-suite.
+//   - REMOTE_TEST_OUT_SCHEME selects "https"/"wss" to use TLS, instead of
+//     the plaintext "http"/"ws" default.
+//   - REMOTE_TEST_OUT_TOKEN, if set, is sent as a Bearer token on every
+//     request.
+func NewTransport(addr string) (Transport, error) {
+	token := os.Getenv("REMOTE_TEST_OUT_TOKEN")
+	secure := os.Getenv("REMOTE_TEST_OUT_SCHEME") == "https" || os.Getenv("REMOTE_TEST_OUT_SCHEME") == "wss"
+
+	switch os.Getenv("REMOTE_TEST_OUT_TRANSPORT") {
+	case "ndjson":
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://%s/report-suite", scheme, addr)
+		return newNDJSONTransport(url, token, http.DefaultClient), nil
+	case "websocket":
+		scheme := "ws"
+		if secure {
+			scheme = "wss"
+		}
+		url := fmt.Sprintf("%s://%s/report-suite", scheme, addr)
+		return newWebsocketTransport(url, token, websocket.DefaultDialer), nil
+	case "", "http":
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://%s/report-suite", scheme, addr)
+		return newHTTPTransport(url, token, http.DefaultClient), nil
+	default:
+		return nil, fmt.Errorf("unknown REMOTE_TEST_OUT_TRANSPORT %q", os.Getenv("REMOTE_TEST_OUT_TRANSPORT"))
+	}
+}
+
+// httpTransport is the original RemoteJSONReporter behavior: one POST per
+// message.
+type httpTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newHTTPTransport(url, token string, client *http.Client) *httpTransport {
+	return &httpTransport{url: url, token: token, client: client}
+}
+
+func (t *httpTransport) Send(msg SuiteMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to post request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return nil
+	default:
+		return fmt.Errorf("server did not accept request: %q", resp.Status)
+	}
+}
+
+func (t *httpTransport) Close() error { return nil }
+
+// ndjsonTransport keeps a single long-lived HTTP connection open (via a
+// chunked request body) and streams one JSON-encoded SuiteMessage per
+// line, so the server can consume updates as they happen instead of
+// waiting for discrete POSTs.
+//
+// Because the whole stream is one HTTP request/response pair, a non-2xx
+// response is only discovered once the server has read the entire body --
+// in practice, at Close. A dial failure or a connection that drops mid-
+// stream is detected as soon as the request goroutine observes it (it
+// closes the pipe's read side with that error, which turns the *next*
+// Write -- and hence the *next* Send -- into an error), so at most one
+// buffered update is ever mistaken for delivered after a connection dies
+// outright; Close is what surfaces a late non-2xx.
+type ndjsonTransport struct {
+	url    string
+	token  string
+	client *http.Client
+
+	mu   sync.Mutex
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newNDJSONTransport(url, token string, client *http.Client) *ndjsonTransport {
+	return &ndjsonTransport{url: url, token: token, client: client}
+}
+
+// connect opens the streamed request. Must be called with t.mu held.
+func (t *ndjsonTransport) connect() error {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, t.url, pr)
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := t.client.Do(req)
+		if err != nil {
+			err = fmt.Errorf("unable to post request: %w", err)
+			// Nobody will ever read pr if Do failed before (or while)
+			// sending the body, so unblock any Write that's pending --
+			// or will ever happen -- against it with the real error
+			// instead of letting it hang forever.
+			pr.CloseWithError(err)
+			done <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+			done <- nil
+		default:
+			done <- fmt.Errorf("server did not accept request: %q", resp.Status)
+		}
+	}()
+
+	t.pw = pw
+	t.done = done
+	return nil
+}
+
+func (t *ndjsonTransport) Send(msg SuiteMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pw == nil {
+		if err := t.connect(); err != nil {
+			return err
+		}
+	}
+
+	// If the request has already finished (successfully or not) since the
+	// last Send, don't write into it as if it were still live: reconnect
+	// on a premature success (the server shouldn't respond until we close
+	// the body) and propagate a known failure immediately, rather than
+	// silently going on to "succeed" a write that has nowhere to go.
+	select {
+	case err := <-t.done:
+		t.pw = nil
+		if err == nil {
+			err = fmt.Errorf("connection closed unexpectedly")
+		}
+		return err
+	default:
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal request: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := t.pw.Write(data); err != nil {
+		t.pw = nil
+		return fmt.Errorf("unable to write to stream: %w", err)
+	}
+
+	return nil
+}
+
+func (t *ndjsonTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pw == nil {
+		return nil
+	}
+	err := t.pw.Close()
+	if doneErr := <-t.done; doneErr != nil && err == nil {
+		err = doneErr
+	}
+	t.pw = nil
+	return err
+}
+
+// websocketTransport sends one SuiteMessage per WebSocket text frame over
+// a connection kept open for the life of the suite.
+type websocketTransport struct {
+	url    string
+	header http.Header
+	dialer *websocket.Dialer
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func newWebsocketTransport(url, token string, dialer *websocket.Dialer) *websocketTransport {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return &websocketTransport{url: url, header: header, dialer: dialer}
+}
+
+// connect opens the WebSocket connection. Must be called with t.mu held.
+func (t *websocketTransport) connect() error {
+	conn, _, err := t.dialer.Dial(t.url, t.header)
+	if err != nil {
+		return fmt.Errorf("unable to dial: %w", err)
+	}
+	t.conn = conn
+	return nil
+}
+
+func (t *websocketTransport) Send(msg SuiteMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		if err := t.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := t.conn.WriteJSON(msg); err != nil {
+		t.conn.Close()
+		t.conn = nil
+		return fmt.Errorf("unable to write message: %w", err)
+	}
+
+	return nil
+}
+
+func (t *websocketTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package printer
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHTTPTransportSend(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("unable to read request body: %v", err)
+		}
+		gotBody = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	tr := newHTTPTransport(srv.URL, "", srv.Client())
+	if err := tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{Name: "s"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(gotBody, `"name":"s"`) {
+		t.Errorf("expected posted body to contain the suite name, got %q", gotBody)
+	}
+}
+
+func TestHTTPTransportSendNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tr := newHTTPTransport(srv.URL, "", srv.Client())
+	if err := tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{}}); err == nil {
+		t.Fatalf("expected an error for a non-2xx response")
+	}
+}
+
+// TestNDJSONTransportCloseSurfacesLateNon2xx covers the residual limitation
+// documented on ndjsonTransport: a non-2xx response is only discovered once
+// the server has read the whole streamed body, i.e. at Close.
+func TestNDJSONTransportCloseSurfacesLateNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		scanner := bufio.NewScanner(req.Body)
+		for scanner.Scan() {
+			// drain the stream fully so the handler only returns once the
+			// client calls Close.
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tr := newNDJSONTransport(srv.URL, "", srv.Client())
+
+	if err := tr.Send(SuiteMessage{Action: ActionSuiteUpdate, Suite: &Suite{}}); err != nil {
+		t.Fatalf("unexpected error from Send: %v", err)
+	}
+	if err := tr.Close(); err == nil {
+		t.Fatalf("expected Close to surface the server's non-2xx response")
+	}
+}
+
+func TestNDJSONTransportCloseSucceedsOn2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		scanner := bufio.NewScanner(req.Body)
+		for scanner.Scan() {
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	tr := newNDJSONTransport(srv.URL, "", srv.Client())
+	if err := tr.Send(SuiteMessage{Action: ActionSuiteUpdate, Suite: &Suite{}}); err != nil {
+		t.Fatalf("unexpected error from Send: %v", err)
+	}
+	if err := tr.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %v", err)
+	}
+}
+
+// TestNDJSONTransportDialFailureUnblocksSend covers the connect() fix: a
+// dial failure must not leave a subsequent Send hanging forever on a pipe
+// nobody will ever read.
+func TestNDJSONTransportDialFailureUnblocksSend(t *testing.T) {
+	// Bind a listener and immediately close it, so the resulting address is
+	// guaranteed to refuse connections rather than relying on port 0, which
+	// some platforms instead treat as "pick any free port".
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to reserve an address: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	tr := newNDJSONTransport("http://"+addr+"/report-suite", "", &http.Client{Timeout: time.Second})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{}})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected an error when the server is unreachable")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Send did not return after a dial failure -- it's hanging on the pipe")
+	}
+}
+
+func TestWebsocketTransportSend(t *testing.T) {
+	var upgrader websocket.Upgrader
+	received := make(chan SuiteMessage, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var msg SuiteMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Errorf("read failed: %v", err)
+			return
+		}
+		received <- msg
+	}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	tr := newWebsocketTransport(wsURL, "", websocket.DefaultDialer)
+	defer tr.Close()
+
+	if err := tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{Name: "s"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Suite.Name != "s" {
+			t.Errorf("expected suite name %q, got %q", "s", msg.Suite.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server never received the message")
+	}
+}
+
+func TestWebsocketTransportSendAfterServerCloses(t *testing.T) {
+	var upgrader websocket.Upgrader
+	var conns int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		conns++
+		// Close immediately so the next write on this connection fails.
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	tr := newWebsocketTransport(wsURL, "", websocket.DefaultDialer)
+	defer tr.Close()
+
+	// The first Send connects, then fails to write on the now-closed
+	// connection; tr.conn should be cleared so the next Send reconnects
+	// rather than reusing a dead connection.
+	_ = tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{}})
+	if tr.conn != nil {
+		// Give the server's close a moment to be observed by a retry, if
+		// the first write happened to race ahead of it.
+		time.Sleep(100 * time.Millisecond)
+		_ = tr.Send(SuiteMessage{Action: ActionSuiteStart, Suite: &Suite{}})
+	}
+
+	if conns < 1 {
+		t.Fatalf("expected at least one connection attempt")
+	}
+}
+
+func TestNewTransportUnknownKind(t *testing.T) {
+	t.Setenv("REMOTE_TEST_OUT_TRANSPORT", "carrier-pigeon")
+	if _, err := NewTransport("example.com"); err == nil {
+		t.Fatalf("expected an error for an unknown transport kind")
+	}
+}
+
+func TestNewTransportNDJSONUsesScheme(t *testing.T) {
+	t.Setenv("REMOTE_TEST_OUT_TRANSPORT", "ndjson")
+	t.Setenv("REMOTE_TEST_OUT_SCHEME", "https")
+
+	tr, err := NewTransport("example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nt, ok := tr.(*ndjsonTransport)
+	if !ok {
+		t.Fatalf("expected an *ndjsonTransport, got %T", tr)
+	}
+	u, err := url.Parse(nt.url)
+	if err != nil {
+		t.Fatalf("unable to parse transport URL %q: %v", nt.url, err)
+	}
+	if u.Scheme != "https" {
+		t.Errorf("expected scheme https, got %q", u.Scheme)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v2 is the Ginkgo v2 equivalent of the parent printer package.
+// Ginkgo v2 replaced the old Reporter interface (SpecSuiteWillBegin,
+// SpecDidComplete, ...) with ReportBeforeSuite/ReportAfterEach/
+// ReportAfterSuite callbacks that hand back types.Report/types.SpecReport,
+// so the reporters in here are built against that model instead.
+//
+// They reuse the wire types from the parent package (Suite, PartStatus,
+// SuiteMessage, ...) so that a server speaking the v1 protocol can consume
+// v2 reports without changes -- it'll simply see the new optional fields
+// (Labels, ReportEntries, SpecialFailureReasons, ParallelProcess) populated.
+package v2
+
+import (
+	"os"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+)
+
+// RemoteJSONReporter is the Ginkgo v2 equivalent of
+// printer.RemoteJSONReporter: it sends JSON status reports to
+// http://localhost:<Port>/report-suite using the same wire format, but is
+// driven by Ginkgo v2's ReportBeforeSuite/ReportAfterEach/ReportAfterSuite
+// callbacks instead of the removed v1 Reporter interface.
+//
+// It's backed by the same printer.Sender as the v1 reporter, so it gets the
+// same pluggable Transport, buffering, and retry-with-backoff behavior
+// instead of blocking a report callback on the network.
+type RemoteJSONReporter struct {
+	Addr string
+
+	// Transport overrides how messages are sent -- see printer.Sender.
+	Transport printer.Transport
+	// BufferSize bounds how many not-yet-acknowledged spec updates are
+	// buffered for replay after a reconnect -- see printer.Sender.
+	BufferSize int
+
+	senderOnce sync.Once
+	sender     printer.Sender
+}
+
+// sndr lazily builds the Sender that does the actual work, from the fields
+// above.
+func (r *RemoteJSONReporter) sndr() *printer.Sender {
+	r.senderOnce.Do(func() {
+		r.sender = printer.Sender{Addr: r.Addr, Transport: r.Transport, BufferSize: r.BufferSize}
+	})
+	return &r.sender
+}
+
+// ReportBeforeSuite should be registered with ginkgo.ReportBeforeSuite. It
+// records the suite's identity and labels ahead of any specs running.
+func (r *RemoteJSONReporter) ReportBeforeSuite(report types.Report) {
+	r.sndr().BeginSuite(report.SuiteDescription, report.SuitePath, report.SuiteLabels)
+}
+
+// ReportAfterEach should be registered with ginkgo.ReportAfterEach. It
+// records the outcome of a single spec, including its labels and timeline --
+// or, for the synthetic specs Ginkgo v2 uses to report BeforeSuite/
+// AfterSuite, the outcome of suite setup/teardown.
+func (r *RemoteJSONReporter) ReportAfterEach(specReport types.SpecReport) {
+	p := specReportToPartStatus(specReport)
+
+	switch specReport.LeafNodeType {
+	case types.NodeTypeBeforeSuite, types.NodeTypeSynchronizedBeforeSuite:
+		r.sndr().SetBeforeSuite(p)
+	case types.NodeTypeAfterSuite, types.NodeTypeSynchronizedAfterSuite:
+		r.sndr().SetAfterSuite(p)
+	default:
+		r.sndr().PushSpec(p)
+	}
+}
+
+// ReportAfterSuite should be registered with ginkgo.ReportAfterSuite. It
+// records the suite's final tallies and any suite-wide failure reasons.
+func (r *RemoteJSONReporter) ReportAfterSuite(report types.Report) {
+	r.sndr().EndSuite(report.RunTime, reportToStats(report), report.SpecialSuiteFailureReasons)
+}
+
+// reportToStats tallies up the spec reports into the shared SuiteStats
+// shape, since Ginkgo v2 no longer hands us pre-computed totals. Suite
+// setup/teardown nodes are reported separately (as Suite.BeforeSuite/
+// AfterSuite), so they're excluded from the per-spec tally here.
+func reportToStats(report types.Report) *printer.SuiteStats {
+	stats := &printer.SuiteStats{}
+	for _, specReport := range report.SpecReports {
+		switch specReport.LeafNodeType {
+		case types.NodeTypeBeforeSuite, types.NodeTypeSynchronizedBeforeSuite,
+			types.NodeTypeAfterSuite, types.NodeTypeSynchronizedAfterSuite:
+			continue
+		}
+
+		switch specReport.State {
+		case types.SpecStatePending:
+			stats.Pending++
+		case types.SpecStateSkipped:
+			stats.Skipped++
+		case types.SpecStatePassed:
+			stats.Passed++
+		default:
+			stats.Failed++
+		}
+		if specReport.NumAttempts > 1 && specReport.State == types.SpecStatePassed {
+			stats.Flakes++
+		}
+		stats.Total++
+	}
+	return stats
+}
+
+// specReportToPartStatus converts a Ginkgo v2 SpecReport into the shared
+// PartStatus wire type, carrying over its labels and timeline (report
+// entries and captured GinkgoWriter output) alongside the usual outcome.
+func specReportToPartStatus(specReport types.SpecReport) printer.PartStatus {
+	p := printer.PartStatus{
+		Components:      specReportToComponents(specReport),
+		RunTime:         specReport.RunTime,
+		Output:          specReport.CapturedGinkgoWriterOutput,
+		Labels:          specReport.Labels(),
+		ReportEntries:   reportEntriesToReportEntries(specReport.ReportEntries),
+		ParallelProcess: specReport.ParallelProcess,
+	}
+
+	p.State = specStateToSpecState(specReport.State)
+	if !specReport.Failure.IsZero() {
+		p.Failure = failureToFailureInfo(specReport.Failure)
+	}
+
+	return p
+}
+
+// specReportToComponents flattens a SpecReport's container hierarchy and
+// leaf node into the shared StatusComponent path, mirroring
+// printer.summaryToComponents for v1 SpecSummarys.
+func specReportToComponents(specReport types.SpecReport) []printer.StatusComponent {
+	res := make([]printer.StatusComponent, 0, len(specReport.ContainerHierarchyTexts)+1)
+	for i, txt := range specReport.ContainerHierarchyTexts {
+		loc := types.CodeLocation{}
+		if i < len(specReport.ContainerHierarchyLocations) {
+			loc = specReport.ContainerHierarchyLocations[i]
+		}
+		res = append(res, printer.StatusComponent{Text: txt, Location: locToLoc(loc)})
+	}
+	if specReport.LeafNodeText != "" {
+		res = append(res, printer.StatusComponent{Text: specReport.LeafNodeText, Location: locToLoc(specReport.LeafNodeLocation)})
+	}
+	return res
+}
+
+// reportEntriesToReportEntries converts Ginkgo v2's ReportEntries into the
+// shared, dependency-free ReportEntry wire type.
+func reportEntriesToReportEntries(entries []types.ReportEntry) []printer.ReportEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	res := make([]printer.ReportEntry, len(entries))
+	for i, entry := range entries {
+		res[i] = printer.ReportEntry{
+			Name:     entry.Name,
+			Location: locToLoc(entry.Location),
+			Time:     entry.Time,
+			Value:    entry.StringRepresentation(),
+		}
+	}
+	return res
+}
+
+// specStateToSpecState maps a Ginkgo v2 spec state onto the shared
+// SpecState enum, which was extended with Aborted/Interrupted for v2's
+// benefit.
+func specStateToSpecState(state types.SpecState) printer.SpecState {
+	switch state {
+	case types.SpecStatePending:
+		return printer.SpecStatePending
+	case types.SpecStateSkipped:
+		return printer.SpecStateSkipped
+	case types.SpecStatePassed:
+		return printer.SpecStatePassed
+	case types.SpecStateFailed:
+		return printer.SpecStateFailed
+	case types.SpecStatePanicked:
+		return printer.SpecStatePanicked
+	case types.SpecStateTimedout:
+		return printer.SpecStateTimedOut
+	case types.SpecStateInterrupted:
+		return printer.SpecStateInterrupted
+	case types.SpecStateAborted:
+		return printer.SpecStateAborted
+	default:
+		return printer.SpecStateFailed
+	}
+}
+
+// failureToFailureInfo converts a Ginkgo v2 Failure into the shared
+// FailureInfo wire type. v2 identifies the failing node by its NodeType
+// rather than the v1 ComponentType/ComponentIndex pair, so the Component
+// index is left unset.
+func failureToFailureInfo(failure types.Failure) *printer.FailureInfo {
+	return &printer.FailureInfo{
+		Message:  failure.Message,
+		Location: locToLoc(failure.Location),
+		Panic:    failure.ForwardedPanic,
+		Component: printer.FailureComponent{
+			Type:     nodeTypeToComponentType(failure.FailureNodeType),
+			Location: locToLoc(failure.FailureNodeLocation),
+		},
+	}
+}
+
+// nodeTypeToComponentType maps a Ginkgo v2 node type onto the shared
+// ComponentType enum used by the v1 reporter.
+func nodeTypeToComponentType(typ types.NodeType) printer.ComponentType {
+	switch typ {
+	case types.NodeTypeContainer:
+		return printer.ComponentTypeContainer
+	case types.NodeTypeBeforeEach, types.NodeTypeSynchronizedBeforeSuite:
+		return printer.ComponentTypeBeforeEach
+	case types.NodeTypeJustBeforeEach:
+		return printer.ComponentTypeJustBeforeEach
+	case types.NodeTypeAfterEach, types.NodeTypeSynchronizedAfterSuite:
+		return printer.ComponentTypeAfterEach
+	case types.NodeTypeJustAfterEach:
+		return printer.ComponentTypeJustAfterEach
+	case types.NodeTypeIt:
+		return printer.ComponentTypeIt
+	default:
+		return printer.ComponentTypeOther
+	}
+}
+
+// locToLoc converts a Ginkgo v2 CodeLocation to the shared serializable
+// form.
+func locToLoc(loc types.CodeLocation) printer.Location {
+	return printer.Location{
+		File:  loc.FileName,
+		Line:  loc.LineNumber,
+		Stack: loc.FullStackTrace,
+	}
+}
+
+// hasEnv checks that the given env vars are set to *something*.
+func hasEnv(vars ...string) bool {
+	for _, envVar := range vars {
+		if os.Getenv(envVar) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// CIReporters registers the Ginkgo v2 reporters appropriate for the current
+// test environment with the currently-being-built suite, mirroring
+// printer.CIReporters but for Ginkgo v2's report model. Unlike its v1
+// counterpart it has nothing to return -- v2 reporters are wired up via
+// ReportBeforeSuite/ReportAfterEach/ReportAfterSuite registration instead of
+// a Reporter slice passed to RunSpecs -- so it must be called before
+// ginkgo.RunSpecs.
+//
+// In particular, Remote JSON output is turned on when $CI and
+// $REMOTE_TEST_OUT_ADDR are set.
+func CIReporters(suiteName string) {
+	if !hasEnv("CI") {
+		return
+	}
+
+	if remoteAddr := os.Getenv("REMOTE_TEST_OUT_ADDR"); remoteAddr != "" {
+		r := &RemoteJSONReporter{Addr: remoteAddr}
+		ginkgo.ReportBeforeSuite(func(report types.Report) {
+			r.ReportBeforeSuite(report)
+		})
+		ginkgo.ReportAfterEach(r.ReportAfterEach)
+		ginkgo.ReportAfterSuite(suiteName, func(report types.Report) {
+			r.ReportAfterSuite(report)
+		})
+	}
+}